@@ -0,0 +1,255 @@
+package secret
+
+import (
+	"context"
+	errstd "errors"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AnnotationSecretName names the Secret that should be created/updated for the
+// ConfigMap or Service it is set on.
+const AnnotationSecretName = "secret-generator.v1.mittwald.de/secret-name"
+
+// generatorAnnotations lists the annotations the Secret reconciler and its
+// generators actually read. Only these are copied from the owning ConfigMap/Service
+// onto the generated Secret; AnnotationSecretName itself and unrelated annotations
+// (e.g. kubectl's last-applied-configuration) stay on the owner.
+var generatorAnnotations = []string{
+	AnnotationSecretType,
+	AnnotationSecretAutoGenerate,
+	AnnotationSecretRegenerate,
+	AnnotationSecretSecure,
+	AnnotationSecretLength,
+	AnnotationSecretEncoding,
+	AnnotationSecretTemplate,
+	AnnotationSecretKeys,
+	AnnotationSecretTTL,
+	AnnotationSecretRotationKeys,
+	AnnotationSecretTLSCommonName,
+	AnnotationSecretTLSSANs,
+	AnnotationSecretTLSValidity,
+	AnnotationSecretTLSCASecret,
+	AnnotationSecretJWTAlgorithm,
+	AnnotationSecretJWTJWKSKey,
+}
+
+// copyGeneratorAnnotations copies the annotations named in generatorAnnotations from
+// src to dst, leaving everything else untouched.
+func copyGeneratorAnnotations(dst, src map[string]string) {
+	for _, key := range generatorAnnotations {
+		if val, ok := src[key]; ok {
+			dst[key] = val
+		}
+	}
+}
+
+// AddAnnotatedOwner creates the annotated-owner controller and adds it to mgr. The
+// Manager will set fields on the Controller and Start it when the Manager is Started.
+func AddAnnotatedOwner(mgr manager.Manager) error {
+	return addAnnotatedOwner(mgr, NewAnnotatedOwnerReconciler(mgr))
+}
+
+// NewAnnotatedOwnerReconciler returns a new reconcile.Reconciler
+func NewAnnotatedOwnerReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileAnnotatedOwner{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+}
+
+func addAnnotatedOwner(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("annotated-owner-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	pred := AnnotatedOwnerPredicate()
+
+	if err := c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForObject{}, pred); err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &corev1.Service{}}, &handler.EnqueueRequestForObject{}, pred); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AnnotatedOwnerPredicate filters ConfigMap/Service events down to objects carrying
+// AnnotationSecretName, so the reconciler isn't invoked for every ConfigMap and
+// Service in the cluster.
+func AnnotatedOwnerPredicate() predicate.Funcs {
+	hasSecretName := func(annotations map[string]string) bool {
+		if annotations == nil {
+			return false
+		}
+		_, ok := annotations[AnnotationSecretName]
+		return ok
+	}
+
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return hasSecretName(e.Object.GetAnnotations())
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return hasSecretName(e.Object.GetAnnotations())
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return hasSecretName(e.Object.GetAnnotations())
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return hasSecretName(e.ObjectOld.GetAnnotations()) || hasSecretName(e.ObjectNew.GetAnnotations())
+		},
+	}
+}
+
+// blank assignment to verify that ReconcileAnnotatedOwner implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileAnnotatedOwner{}
+
+// ReconcileAnnotatedOwner watches ConfigMaps and Services for AnnotationSecretName
+// and creates/updates a companion Secret, owned by the annotated object, from the
+// same generator annotations the Secret reconciler understands. This lets users
+// declare "this Service needs a signing key" in one place rather than authoring a
+// separate Secret manifest.
+type ReconcileAnnotatedOwner struct {
+	// This Client, initialized using mgr.Client() above, is a split Client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile reads the ConfigMap or Service named by request and, if it carries
+// AnnotationSecretName, creates or updates the Secret it names.
+func (r *ReconcileAnnotatedOwner) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	owner, secretName, err := r.findOwner(request)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if owner == nil {
+		// neither a ConfigMap nor a Service carrying the annotation exists for this request
+		return reconcile.Result{}, nil
+	}
+
+	reqLogger = reqLogger.WithValues("secretName", secretName)
+
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: request.Namespace, Name: secretName}, secret)
+	isNew := false
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+
+		isNew = true
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: request.Namespace,
+			},
+		}
+	}
+
+	desired := secret.DeepCopy()
+	if desired.Annotations == nil {
+		desired.Annotations = make(map[string]string)
+	}
+	copyGeneratorAnnotations(desired.Annotations, owner.GetAnnotations())
+	if desired.Data == nil {
+		desired.Data = make(map[string][]byte)
+	}
+
+	if err := controllerutil.SetControllerReference(owner, desired, r.scheme); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	sType := Type(desired.Annotations[AnnotationSecretType])
+	if err := sType.Validate(); err != nil {
+		if _, ok := desired.Annotations[AnnotationSecretAutoGenerate]; !ok && sType == "" {
+			return reconcile.Result{}, nil
+		}
+
+		// keep backwards compatibility by defaulting to string type
+		desired.Annotations[AnnotationSecretType] = string(TypeString)
+		sType = TypeString
+	}
+
+	generator, ok := newGenerator(sType, reqLogger.WithValues("type", sType))
+	if !ok {
+		reqLogger.Error(errstd.New("SecretTypeNotSpecified"), "Secret type was not specified")
+		return reconcile.Result{Requeue: true}, errstd.New("SecretTypeNotSpecified")
+	}
+
+	res, err := generator.generateData(desired)
+	if err != nil {
+		return res, err
+	}
+
+	if isNew {
+		desired.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
+		reqLogger.Info("creating owned secret")
+		if err := r.client.Create(context.TODO(), desired); err != nil {
+			return reconcile.Result{Requeue: true}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !reflect.DeepEqual(secret.Annotations, desired.Annotations) || !reflect.DeepEqual(secret.Data, desired.Data) ||
+		!reflect.DeepEqual(secret.OwnerReferences, desired.OwnerReferences) {
+		reqLogger.Info("updating owned secret")
+		desired.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
+		if err := r.client.Update(context.TODO(), desired); err != nil {
+			reqLogger.Error(err, "could not update owned secret")
+			return reconcile.Result{Requeue: true}, err
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// findOwner resolves request to whichever of ConfigMap or Service it refers to and
+// carries AnnotationSecretName, returning the Secret name it requests alongside it.
+// The request only carries a namespace/name, not a GVK, so a ConfigMap and a Service
+// can share it; both kinds are always checked, and an object is only ever returned
+// as the owner when it actually carries the annotation, so an unannotated object of
+// one kind can never suppress - or be mistaken for - an annotated object of the
+// other. Both owner and err are nil if neither kind carries the annotation.
+func (r *ReconcileAnnotatedOwner) findOwner(request reconcile.Request) (metav1.Object, string, error) {
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, "", err
+	}
+	if err == nil {
+		if name, ok := cm.Annotations[AnnotationSecretName]; ok {
+			return cm, name, nil
+		}
+	}
+
+	svc := &corev1.Service{}
+	err = r.client.Get(context.TODO(), request.NamespacedName, svc)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, "", err
+	}
+	if err == nil {
+		if name, ok := svc.Annotations[AnnotationSecretName]; ok {
+			return svc, name, nil
+		}
+	}
+
+	return nil, "", nil
+}
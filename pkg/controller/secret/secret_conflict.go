@@ -0,0 +1,160 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// maxConflictRetries caps how many times updateWithConflictRetry re-derives and
+// retries an Update after an apiserver conflict, so a persistently contended secret
+// can't wedge a reconcile loop forever.
+const maxConflictRetries = 3
+
+var conflictRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "generator_conflict_retries_total",
+	Help: "Total number of times an Update was retried after an apiserver conflict while reconciling a generated secret.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(conflictRetriesTotal)
+}
+
+// changedDataKeys returns the keys in desired whose value differs from (or is
+// missing in) previous, i.e. the keys a generator actually wrote this pass.
+func changedDataKeys(previous, desired map[string][]byte) []string {
+	var keys []string
+	for key, value := range desired {
+		if old, ok := previous[key]; !ok || !bytes.Equal(old, value) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// updateWithConflictRetry updates desired, and on a Conflict response re-fetches the
+// latest version of the secret and retries. A conflict must never cause the bytes a
+// generator has already produced this pass to be thrown away and re-randomized on
+// the next reconcile, so if the latest version already reflects everything this pass
+// changed, that counts as success with no further write; otherwise this pass's
+// changes - including annotations it deleted, such as AnnotationSecretRegenerate -
+// are merged onto the latest version before retrying. original is the secret as read
+// at the start of this reconcile, before the generator or rotation touched it, and is
+// used only to work out which annotations this pass actually added, changed or
+// removed.
+func (r *ReconcileSecret) updateWithConflictRetry(ctx context.Context, reqLogger logr.Logger, original, desired *corev1.Secret, generatedKeys []string) error {
+	changedAnnotations, deletedAnnotations := diffAnnotations(original.Annotations, desired.Annotations)
+
+	current := desired
+
+	for attempt := 0; ; attempt++ {
+		err := r.client.Update(ctx, current)
+		if err == nil {
+			return nil
+		}
+		if !errors.IsConflict(err) || attempt >= maxConflictRetries {
+			return err
+		}
+
+		conflictRetriesTotal.Inc()
+		reqLogger.Info("update conflict, re-deriving from latest version", "attempt", attempt+1)
+		time.Sleep(conflictBackoff(attempt))
+
+		latest := &corev1.Secret{}
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: current.Namespace, Name: current.Name}, latest); err != nil {
+			return err
+		}
+
+		if generationAlreadyApplied(latest, desired, generatedKeys, changedAnnotations, deletedAnnotations) {
+			reqLogger.Info("generated values already present on latest version, skipping update")
+			return nil
+		}
+
+		current = mergeGeneratedValues(latest, desired, generatedKeys, changedAnnotations, deletedAnnotations)
+	}
+}
+
+// diffAnnotations compares the annotations on a secret before and after this pass's
+// generation/rotation/defaulting ran, returning the keys that were added or changed
+// (with their new value) and the keys that were removed.
+func diffAnnotations(original, desired map[string]string) (changed map[string]string, deleted []string) {
+	changed = make(map[string]string)
+	for key, val := range desired {
+		if origVal, ok := original[key]; !ok || origVal != val {
+			changed[key] = val
+		}
+	}
+
+	for key := range original {
+		if _, ok := desired[key]; !ok {
+			deleted = append(deleted, key)
+		}
+	}
+
+	return changed, deleted
+}
+
+// generationAlreadyApplied reports whether latest already carries every key this
+// pass generated and every annotation change (additions, changes and deletions) it
+// made, meaning the conflicting write was effectively already applied by someone
+// else.
+func generationAlreadyApplied(latest, desired *corev1.Secret, generatedKeys []string, changedAnnotations map[string]string, deletedAnnotations []string) bool {
+	for _, key := range generatedKeys {
+		if !bytes.Equal(latest.Data[key], desired.Data[key]) {
+			return false
+		}
+	}
+
+	for key, val := range changedAnnotations {
+		if latest.Annotations[key] != val {
+			return false
+		}
+	}
+
+	for _, key := range deletedAnnotations {
+		if _, ok := latest.Annotations[key]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeGeneratedValues layers the keys generated this pass, and the annotation
+// changes (including deletions) this pass made, onto the latest version of the
+// secret, so the retried Update neither drops the other writer's change nor throws
+// away this pass's generated values or annotation bookkeeping (e.g. clearing
+// AnnotationSecretRegenerate).
+func mergeGeneratedValues(latest, desired *corev1.Secret, generatedKeys []string, changedAnnotations map[string]string, deletedAnnotations []string) *corev1.Secret {
+	merged := latest.DeepCopy()
+
+	if merged.Data == nil {
+		merged.Data = make(map[string][]byte)
+	}
+	for _, key := range generatedKeys {
+		merged.Data[key] = desired.Data[key]
+	}
+
+	if merged.Annotations == nil {
+		merged.Annotations = make(map[string]string)
+	}
+	for key, val := range changedAnnotations {
+		merged.Annotations[key] = val
+	}
+	for _, key := range deletedAnnotations {
+		delete(merged.Annotations, key)
+	}
+
+	return merged
+}
+
+func conflictBackoff(attempt int) time.Duration {
+	return time.Duration(attempt+1) * 100 * time.Millisecond
+}
@@ -0,0 +1,151 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// conflictOnceClient fails the first Update with a Conflict, as if another writer
+// raced in between the caller's Get and Update, then serves Get/Update against
+// whatever is stored in current.
+type conflictOnceClient struct {
+	client.Client // unused methods are never called in these tests
+	current       *corev1.Secret
+	conflicted    bool
+}
+
+func (c *conflictOnceClient) Get(_ context.Context, _ types.NamespacedName, obj runtime.Object) error {
+	secret := obj.(*corev1.Secret)
+	*secret = *c.current.DeepCopy()
+	return nil
+}
+
+func (c *conflictOnceClient) Update(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+	if !c.conflicted {
+		c.conflicted = true
+		return apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, c.current.Name, nil)
+	}
+
+	c.current = obj.(*corev1.Secret).DeepCopy()
+	return nil
+}
+
+func TestUpdateWithConflictRetry_NeverThrowsAwayGeneratedValues(t *testing.T) {
+	original := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s", Namespace: "ns"},
+		Data:       map[string][]byte{"password": []byte("already-generated-value")},
+		Annotations: map[string]string{
+			AnnotationSecretAutoGeneratedAt: "2020-01-01T00:00:00Z",
+			AnnotationSecretRegenerate:      "password",
+		},
+	}
+
+	// a concurrent writer touches an unrelated annotation between our Get and Update
+	raced := original.DeepCopy()
+	raced.Annotations["unrelated"] = "external-writer"
+	fc := &conflictOnceClient{current: raced}
+
+	// this is what the generator produced: it generated "password" and, as
+	// StringGenerator does, deleted AnnotationSecretRegenerate once it acted on it
+	desired := original.DeepCopy()
+	desired.Annotations[AnnotationSecretAutoGeneratedAt] = "2024-01-01T00:00:00Z"
+	delete(desired.Annotations, AnnotationSecretRegenerate)
+
+	r := &ReconcileSecret{client: fc}
+	if err := r.updateWithConflictRetry(context.Background(), log, original, desired, []string{"password"}); err != nil {
+		t.Fatalf("updateWithConflictRetry: %v", err)
+	}
+
+	if got := string(fc.current.Data["password"]); got != "already-generated-value" {
+		t.Fatalf("generated value was re-randomized on conflict: got %q", got)
+	}
+	if fc.current.Annotations["unrelated"] != "external-writer" {
+		t.Fatalf("concurrent writer's annotation was dropped by the retry")
+	}
+	if fc.current.Annotations[AnnotationSecretAutoGeneratedAt] != "2024-01-01T00:00:00Z" {
+		t.Fatalf("expected this pass's %s to be applied", AnnotationSecretAutoGeneratedAt)
+	}
+	if _, ok := fc.current.Annotations[AnnotationSecretRegenerate]; ok {
+		t.Fatalf("expected %s, cleared by the generator this pass, to stay cleared after the retry", AnnotationSecretRegenerate)
+	}
+}
+
+func TestGenerationAlreadyApplied(t *testing.T) {
+	original := &corev1.Secret{
+		Annotations: map[string]string{AnnotationSecretRegenerate: "password"},
+	}
+	desired := &corev1.Secret{
+		Data: map[string][]byte{"password": []byte("value")},
+		Annotations: map[string]string{
+			AnnotationSecretAutoGeneratedAt: "2024-01-01T00:00:00Z",
+			AnnotationSecretSecure:          "yes",
+		},
+	}
+	changed, deleted := diffAnnotations(original.Annotations, desired.Annotations)
+
+	t.Run("matching latest counts as applied", func(t *testing.T) {
+		latest := desired.DeepCopy()
+		if !generationAlreadyApplied(latest, desired, []string{"password"}, changed, deleted) {
+			t.Fatalf("expected latest matching desired to count as already applied")
+		}
+	})
+
+	t.Run("differing generated key is not applied", func(t *testing.T) {
+		latest := desired.DeepCopy()
+		latest.Data["password"] = []byte("different")
+		if generationAlreadyApplied(latest, desired, []string{"password"}, changed, deleted) {
+			t.Fatalf("expected differing generated key to not count as already applied")
+		}
+	})
+
+	t.Run("annotation this pass deleted but still present on latest is not applied", func(t *testing.T) {
+		latest := desired.DeepCopy()
+		latest.Annotations[AnnotationSecretRegenerate] = "password"
+		if generationAlreadyApplied(latest, desired, []string{"password"}, changed, deleted) {
+			t.Fatalf("expected a still-present deleted annotation to not count as already applied")
+		}
+	})
+}
+
+func TestDiffAnnotations(t *testing.T) {
+	original := map[string]string{
+		"keep":         "same",
+		"will-change":  "old",
+		"will-delete":  "gone-soon",
+		"untouched-by": "generator",
+	}
+	desired := map[string]string{
+		"keep":        "same",
+		"will-change": "new",
+		"added":       "brand-new",
+		// untouched-by deliberately omitted to simulate deletion, will-delete too
+	}
+	delete(desired, "untouched-by")
+
+	changed, deleted := diffAnnotations(original, desired)
+
+	if changed["will-change"] != "new" || changed["added"] != "brand-new" {
+		t.Fatalf("unexpected changed set: %v", changed)
+	}
+	if _, ok := changed["keep"]; ok {
+		t.Fatalf("unchanged key %q should not be reported as changed", "keep")
+	}
+
+	wantDeleted := map[string]bool{"will-delete": true, "untouched-by": true}
+	if len(deleted) != len(wantDeleted) {
+		t.Fatalf("unexpected deleted set: %v", deleted)
+	}
+	for _, key := range deleted {
+		if !wantDeleted[key] {
+			t.Fatalf("unexpected key %q reported as deleted", key)
+		}
+	}
+}
@@ -13,9 +13,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -43,11 +45,16 @@ func SSHKeyLength() int {
 // Add creates a new Secret Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, NewReconciler(mgr))
+	if err := add(mgr, NewReconciler(mgr)); err != nil {
+		return err
+	}
+
+	return AddAnnotatedOwner(mgr)
 }
 
 // NewReconciler returns a new reconcile.Reconciler
 func NewReconciler(mgr manager.Manager) reconcile.Reconciler {
+	SetClusterClient(mgr.GetClient())
 	return &ReconcileSecret{client: mgr.GetClient(), scheme: mgr.GetScheme()}
 }
 
@@ -59,8 +66,10 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// Watch for changes to primary resource Secret
-	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{})
+	// Watch for changes to primary resource Secret, but only enqueue secrets this
+	// controller actually cares about so we don't churn the queue on every write
+	// to every Secret in the cluster.
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{}, SecretGenerationPredicate())
 	if err != nil {
 		return err
 	}
@@ -68,6 +77,42 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	return nil
 }
 
+// SecretGenerationPredicate filters Secret events down to those that carry one of the
+// generator annotations, so Reconcile is not invoked for secrets it would immediately
+// bail out of anyway.
+func SecretGenerationPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return hasGeneratorAnnotation(e.Object.GetAnnotations())
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return hasGeneratorAnnotation(e.Object.GetAnnotations())
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return hasGeneratorAnnotation(e.Object.GetAnnotations())
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// also catch the case where the annotation was just removed, so a final
+			// reconcile still runs for it
+			return hasGeneratorAnnotation(e.ObjectOld.GetAnnotations()) || hasGeneratorAnnotation(e.ObjectNew.GetAnnotations())
+		},
+	}
+}
+
+func hasGeneratorAnnotation(annotations map[string]string) bool {
+	if annotations == nil {
+		return false
+	}
+
+	for _, key := range []string{AnnotationSecretAutoGenerate, AnnotationSecretType, AnnotationSecretRegenerate} {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 // blank assignment to verify that ReconcileSecret implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileSecret{}
 
@@ -123,21 +168,13 @@ func (r *ReconcileSecret) Reconcile(request reconcile.Request) (reconcile.Result
 		desired.Data = make(map[string][]byte)
 	}
 
-	var generator Generator
-	switch sType {
-	case TypeSSHKeypair:
-		generator = SSHKeypairGenerator{
-			log: reqLogger.WithValues("type", TypeSSHKeypair),
-		}
-	case TypeString:
-		generator = StringGenerator{
-			log: reqLogger.WithValues("type", TypeString),
-		}
-	case TypeBasicAuth:
-		generator = BasicAuthGenerator{
-			log: reqLogger.WithValues("type", TypeBasicAuth),
-		}
-	default:
+	rotationRequeueAfter, err := handleRotation(reqLogger, desired)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	generator, ok := newGenerator(sType, reqLogger)
+	if !ok {
 		// default case to prevent potential nil-pointer
 		reqLogger.Error(errstd.New("SecretTypeNotSpecified"), "Secret type was not specified")
 		return reconcile.Result{Requeue: true}, errstd.New("SecretTypeNotSpecified")
@@ -152,15 +189,16 @@ func (r *ReconcileSecret) Reconcile(request reconcile.Request) (reconcile.Result
 		!reflect.DeepEqual(instance.Data, desired.Data) {
 		reqLogger.Info("updating secret")
 
+		generatedKeys := changedDataKeys(instance.Data, desired.Data)
 		desired.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
-		err := r.client.Update(context.Background(), desired)
+		err := r.updateWithConflictRetry(context.Background(), reqLogger, instance, desired, generatedKeys)
 		if err != nil {
 			reqLogger.Error(err, "could not update secret")
 			return reconcile.Result{Requeue: true}, err
 		}
 	}
 
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: rotationRequeueAfter}, nil
 }
 
 func GetLengthFromAnnotation(fallback int, annotations map[string]string) (string, error) {
@@ -0,0 +1,90 @@
+package secret
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func secretWithAnnotations(annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+}
+
+func TestSecretGenerationPredicate(t *testing.T) {
+	pred := SecretGenerationPredicate()
+
+	annotated := secretWithAnnotations(map[string]string{AnnotationSecretAutoGenerate: "password"})
+	plain := secretWithAnnotations(nil)
+
+	t.Run("create is enqueued only for annotated secrets", func(t *testing.T) {
+		if !pred.Create(event.CreateEvent{Object: annotated}) {
+			t.Fatalf("expected annotated secret to pass the create predicate")
+		}
+		if pred.Create(event.CreateEvent{Object: plain}) {
+			t.Fatalf("expected plain secret to be filtered out of the create predicate")
+		}
+	})
+
+	t.Run("delete is enqueued only for annotated secrets", func(t *testing.T) {
+		if !pred.Delete(event.DeleteEvent{Object: annotated}) {
+			t.Fatalf("expected annotated secret to pass the delete predicate")
+		}
+		if pred.Delete(event.DeleteEvent{Object: plain}) {
+			t.Fatalf("expected plain secret to be filtered out of the delete predicate")
+		}
+	})
+
+	t.Run("generic is enqueued only for annotated secrets", func(t *testing.T) {
+		if !pred.Generic(event.GenericEvent{Object: annotated}) {
+			t.Fatalf("expected annotated secret to pass the generic predicate")
+		}
+		if pred.Generic(event.GenericEvent{Object: plain}) {
+			t.Fatalf("expected plain secret to be filtered out of the generic predicate")
+		}
+	})
+
+	t.Run("update is enqueued when either side is annotated", func(t *testing.T) {
+		if !pred.Update(event.UpdateEvent{ObjectOld: annotated, ObjectNew: annotated}) {
+			t.Fatalf("expected an update between annotated secrets to pass")
+		}
+		if pred.Update(event.UpdateEvent{ObjectOld: plain, ObjectNew: plain}) {
+			t.Fatalf("expected an update between unannotated secrets to be filtered out")
+		}
+	})
+
+	t.Run("update still fires when the annotation was just removed", func(t *testing.T) {
+		if !pred.Update(event.UpdateEvent{ObjectOld: annotated, ObjectNew: plain}) {
+			t.Fatalf("expected removing the annotation to still trigger a final reconcile")
+		}
+	})
+
+	t.Run("update still fires when the annotation was just added", func(t *testing.T) {
+		if !pred.Update(event.UpdateEvent{ObjectOld: plain, ObjectNew: annotated}) {
+			t.Fatalf("expected adding the annotation to trigger a reconcile")
+		}
+	})
+}
+
+func TestHasGeneratorAnnotation(t *testing.T) {
+	cases := map[string]struct {
+		annotations map[string]string
+		want        bool
+	}{
+		"nil annotations":       {nil, false},
+		"no generator keys":     {map[string]string{"foo": "bar"}, false},
+		"auto-generate":         {map[string]string{AnnotationSecretAutoGenerate: "password"}, true},
+		"type":                  {map[string]string{AnnotationSecretType: string(TypeString)}, true},
+		"regenerate":            {map[string]string{AnnotationSecretRegenerate: "yes"}, true},
+		"unrelated plus marker": {map[string]string{"foo": "bar", AnnotationSecretType: string(TypeString)}, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := hasGeneratorAnnotation(tc.annotations); got != tc.want {
+				t.Fatalf("hasGeneratorAnnotation(%v) = %v, want %v", tc.annotations, got, tc.want)
+			}
+		})
+	}
+}
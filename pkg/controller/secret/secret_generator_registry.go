@@ -0,0 +1,43 @@
+package secret
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// GeneratorConstructor builds a Generator for a logger already scoped to the secret
+// being reconciled, callers pass a logger that already carries a "type" value (see
+// newGenerator), so constructors must not add their own. It is the shape
+// RegisterGenerator expects so operators embedding this controller can add custom
+// secret types without forking it.
+type GeneratorConstructor func(logr.Logger) Generator
+
+var generators = map[Type]GeneratorConstructor{
+	TypeSSHKeypair: func(log logr.Logger) Generator {
+		return SSHKeypairGenerator{log: log}
+	},
+	TypeString: func(log logr.Logger) Generator {
+		return StringGenerator{log: log}
+	},
+	TypeBasicAuth: func(log logr.Logger) Generator {
+		return BasicAuthGenerator{log: log}
+	},
+}
+
+// RegisterGenerator registers the constructor used to build a Generator for t,
+// overwriting any existing registration. Call it from an init() function to add a
+// custom secret type to the controller.
+func RegisterGenerator(t Type, constructor GeneratorConstructor) {
+	generators[t] = constructor
+}
+
+// newGenerator looks up the constructor registered for t and builds a Generator
+// using log, which the caller is expected to have already scoped with a "type"
+// value. The bool return is false if no generator is registered for t.
+func newGenerator(t Type, log logr.Logger) (Generator, bool) {
+	constructor, ok := generators[t]
+	if !ok {
+		return nil, false
+	}
+
+	return constructor(log), true
+}
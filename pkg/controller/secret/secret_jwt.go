@@ -0,0 +1,156 @@
+package secret
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TypeJWT generates a signing keypair plus a JWKS document, for services that need
+// to hand out a public key so others can verify the JWTs they sign.
+const TypeJWT Type = "jwt"
+
+const (
+	// AnnotationSecretJWTAlgorithm picks the key algorithm: "RS256" (RSA, default)
+	// or "EdDSA" (Ed25519).
+	AnnotationSecretJWTAlgorithm = "secret-generator.v1.mittwald.de/jwt-algorithm"
+	// AnnotationSecretJWTJWKSKey overrides the Data key the JWKS JSON blob is
+	// written under. Defaults to jwtDefaultJWKSKey.
+	AnnotationSecretJWTJWKSKey = "secret-generator.v1.mittwald.de/jwks-key"
+
+	jwtPrivateKeyDataKey = "jwt.key"
+	jwtPublicKeyDataKey  = "jwt.pub"
+	jwtDefaultJWKSKey    = "jwks.json"
+
+	jwtAlgRS256 = "RS256"
+	jwtAlgEdDSA = "EdDSA"
+
+	jwtRSAKeyBits = 2048
+)
+
+// JWTSigningKeyGenerator creates an RSA or Ed25519 signing keypair plus a JWKS JSON
+// document describing the public key, so consumers can verify JWTs signed with it.
+type JWTSigningKeyGenerator struct {
+	log logr.Logger
+}
+
+func (g JWTSigningKeyGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
+	jwksKey := jwtDefaultJWKSKey
+	if val, ok := instance.Annotations[AnnotationSecretJWTJWKSKey]; ok && val != "" {
+		jwksKey = val
+	}
+
+	if len(instance.Data[jwtPrivateKeyDataKey]) != 0 {
+		if _, regen := instance.Annotations[AnnotationSecretRegenerate]; !regen {
+			return reconcile.Result{}, nil
+		}
+		delete(instance.Annotations, AnnotationSecretRegenerate)
+	}
+
+	alg := jwtAlgRS256
+	if val, ok := instance.Annotations[AnnotationSecretJWTAlgorithm]; ok && val != "" {
+		alg = val
+	}
+
+	var (
+		privateKeyPEM []byte
+		publicKeyPEM  []byte
+		jwk           map[string]interface{}
+		err           error
+	)
+
+	switch alg {
+	case jwtAlgRS256:
+		privateKeyPEM, publicKeyPEM, jwk, err = generateRSASigningKey()
+	case jwtAlgEdDSA:
+		privateKeyPEM, publicKeyPEM, jwk, err = generateEd25519SigningKey()
+	default:
+		return reconcile.Result{}, fmt.Errorf("unsupported %s %q, want %q or %q", AnnotationSecretJWTAlgorithm, alg, jwtAlgRS256, jwtAlgEdDSA)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []map[string]interface{}{jwk}})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not marshal jwks: %w", err)
+	}
+
+	instance.Data[jwtPrivateKeyDataKey] = privateKeyPEM
+	instance.Data[jwtPublicKeyDataKey] = publicKeyPEM
+	instance.Data[jwksKey] = jwks
+
+	g.log.Info("generated jwt signing keypair", "algorithm", alg, "jwksKey", jwksKey)
+
+	return reconcile.Result{}, nil
+}
+
+func generateRSASigningKey() (privateKeyPEM, publicKeyPEM []byte, jwk map[string]interface{}, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, jwtRSAKeyBits)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not generate rsa key: %w", err)
+	}
+
+	privateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not marshal rsa public key: %w", err)
+	}
+	publicKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})
+
+	jwk = map[string]interface{}{
+		"kty": "RSA",
+		"alg": jwtAlgRS256,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+	}
+
+	return privateKeyPEM, publicKeyPEM, jwk, nil
+}
+
+func generateEd25519SigningKey() (privateKeyPEM, publicKeyPEM []byte, jwk map[string]interface{}, err error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not generate ed25519 key: %w", err)
+	}
+
+	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not marshal ed25519 private key: %w", err)
+	}
+	privateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER})
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not marshal ed25519 public key: %w", err)
+	}
+	publicKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})
+
+	jwk = map[string]interface{}{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"alg": jwtAlgEdDSA,
+		"use": "sig",
+		"x":   base64.RawURLEncoding.EncodeToString(publicKey),
+	}
+
+	return privateKeyPEM, publicKeyPEM, jwk, nil
+}
+
+func init() {
+	RegisterGenerator(TypeJWT, func(log logr.Logger) Generator {
+		return JWTSigningKeyGenerator{log: log}
+	})
+}
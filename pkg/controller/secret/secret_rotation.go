@@ -0,0 +1,147 @@
+package secret
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationSecretTTL sets how long a generated key stays valid before it is
+	// due for rotation, e.g. "720h". It is read together with
+	// AnnotationSecretAutoGeneratedAt to compute the expiry.
+	AnnotationSecretTTL = "secret-generator.v1.mittwald.de/ttl"
+
+	// AnnotationSecretRotationKeys restricts rotation to a comma-separated subset of
+	// keys. If absent, every currently generated key is rotated.
+	AnnotationSecretRotationKeys = "secret-generator.v1.mittwald.de/rotation-keys"
+
+	// previousValueSuffix is appended to a key to preserve its value for one rotation
+	// cycle after rotation, so consumers can do a zero-downtime key rollover.
+	previousValueSuffix = ".previous"
+
+	// rotationJitterFraction caps the jitter applied to a rotation's RequeueAfter to
+	// +/-10%, so secrets with the same TTL don't all rotate in the same instant.
+	rotationJitterFraction = 0.10
+)
+
+// RotationEnabled reports whether the TTL rotation subsystem is active cluster-wide.
+func RotationEnabled() bool {
+	return viper.GetBool("rotation-enabled")
+}
+
+// handleRotation checks whether instance carries a TTL annotation that has expired.
+// It never bypasses generation: even when the TTL is not due yet, the caller is
+// expected to run the normal generator pass afterwards (so a newly added
+// AnnotationSecretAutoGenerate key, a deleted key, or a user-set
+// AnnotationSecretRegenerate are still honored immediately rather than waiting out
+// the TTL). Instead, the non-zero duration it returns is the time until the next
+// rotation is due, for the caller to fold into its own reconcile.Result.RequeueAfter.
+//
+// If the TTL has already expired, it queues the relevant keys for regeneration
+// (reusing the existing AnnotationSecretRegenerate mechanism) and preserves their
+// current values under "<key>.previous" before they are overwritten, returning 0
+// since generation is due right away rather than after a further wait.
+func handleRotation(reqLogger logr.Logger, instance *corev1.Secret) (time.Duration, error) {
+	if !RotationEnabled() {
+		return 0, nil
+	}
+
+	ttlRaw, ok := instance.Annotations[AnnotationSecretTTL]
+	if !ok {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(ttlRaw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretTTL, err)
+	}
+
+	generatedAtRaw, ok := instance.Annotations[AnnotationSecretAutoGeneratedAt]
+	if !ok {
+		// nothing has been generated yet, let the regular generation path run first
+		return 0, nil
+	}
+
+	generatedAt, err := time.Parse(time.RFC3339, generatedAtRaw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretAutoGeneratedAt, err)
+	}
+
+	expiry := generatedAt.Add(ttl)
+	now := time.Now()
+	if now.Before(expiry) {
+		return rotationJitter(expiry.Sub(now)), nil
+	}
+
+	rotateKeys := rotationKeys(instance)
+	clearPreviousValues(instance, rotateKeys)
+	for _, key := range rotateKeys {
+		if current, ok := instance.Data[key]; ok {
+			instance.Data[key+previousValueSuffix] = current
+		}
+	}
+
+	reqLogger.Info("secret ttl expired, queuing keys for rotation", "keys", rotateKeys)
+	instance.Annotations[AnnotationSecretRegenerate] = strings.Join(rotateKeys, ",")
+
+	return 0, nil
+}
+
+// clearPreviousValues drops the "<key>.previous" value for each of keys, so a value
+// preserved across one rotation cycle doesn't linger into the next one.
+func clearPreviousValues(instance *corev1.Secret, keys []string) {
+	for _, key := range keys {
+		delete(instance.Data, key+previousValueSuffix)
+	}
+}
+
+// rotationKeys returns the keys due for rotation, restricted to keys StringGenerator
+// would actually regenerate, i.e. those listed in AnnotationSecretAutoGenerate.
+// AnnotationSecretRotationKeys, if set, narrows that further to a subset; a key
+// named there that isn't auto-generated is dropped, since rotating it would just
+// leave "<key>.previous" identical to "<key>" forever.
+func rotationKeys(instance *corev1.Secret) []string {
+	genKeys := autoGenerateKeys(instance.Annotations)
+
+	raw, ok := instance.Annotations[AnnotationSecretRotationKeys]
+	if !ok || raw == "" {
+		return genKeys
+	}
+
+	var keys []string
+	for _, key := range strings.Split(raw, ",") {
+		if contains(genKeys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// autoGenerateKeys parses the AnnotationSecretAutoGenerate comma-list, the same way
+// StringGenerator.generateData does.
+func autoGenerateKeys(annotations map[string]string) []string {
+	toGenerate := annotations[AnnotationSecretAutoGenerate]
+	if toGenerate == "" {
+		return nil
+	}
+
+	return strings.Split(toGenerate, ",")
+}
+
+// rotationJitter adds up to +/-10% random jitter to d to spread rotations of secrets
+// that share the same TTL across time instead of firing all at once.
+func rotationJitter(d time.Duration) time.Duration {
+	delta := time.Duration(float64(d) * rotationJitterFraction)
+	if delta <= 0 {
+		return d
+	}
+
+	return d + time.Duration(rand.Int63n(int64(delta)*2)) - delta
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/base32"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -24,45 +25,96 @@ type secretConfig struct {
 	key          string
 	length       int
 	isByteLength bool
+	encoding     string
+	template     string
+}
+
+// AnnotationSecretKeys carries a per-key configuration map, as JSON, so a single
+// Secret can mix keys with different lengths, encodings and templates, e.g.
+// `{"apikey":{"length":"32B","encoding":"hex"},"password":{"length":"24"}}`.
+// A key not listed here falls back to the single-value annotations and cluster
+// defaults, as before.
+const AnnotationSecretKeys = "secret-generator.v1.mittwald.de/keys"
+
+// keyConfig is a single entry of the AnnotationSecretKeys map. Empty fields fall
+// back to the secret-wide annotations and cluster defaults.
+type keyConfig struct {
+	Length   string `json:"length,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Template string `json:"template,omitempty"`
 }
 
 func (pg StringGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
-	toGenerate := instance.Annotations[AnnotationSecretAutoGenerate]
+	var genKeys []string
+	if toGenerate := instance.Annotations[AnnotationSecretAutoGenerate]; toGenerate != "" {
+		genKeys = strings.Split(toGenerate, ",")
+	}
 
-	genKeys := strings.Split(toGenerate, ",")
+	keyConfigs, err := parseKeyConfigs(instance.Annotations)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// keys listed only in AnnotationSecretKeys are generated too, so the comma-list
+	// and the map never drift out of sync with each other
+	for key := range keyConfigs {
+		if !contains(genKeys, key) {
+			genKeys = append(genKeys, key)
+		}
+	}
 
 	if err := ensureUniqueness(genKeys); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	return pg.regenerateKeysWhereRequired(instance, genKeys)
+	return pg.regenerateKeysWhereRequired(instance, genKeys, keyConfigs)
+}
+
+// parseKeyConfigs parses AnnotationSecretKeys, if present, and validates that every
+// encoding it names is one generateRandomSecret understands.
+func parseKeyConfigs(annotations map[string]string) (map[string]keyConfig, error) {
+	raw, ok := annotations[AnnotationSecretKeys]
+	if !ok || raw == "" {
+		return nil, nil
+	}
+
+	var configs map[string]keyConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretKeys, err)
+	}
+
+	for key, conf := range configs {
+		if conf.Encoding != "" && !isValidEncoding(conf.Encoding) {
+			return nil, fmt.Errorf("%s: key %q has unknown encoding %q", AnnotationSecretKeys, key, conf.Encoding)
+		}
+	}
+
+	return configs, nil
+}
+
+func isValidEncoding(encoding string) bool {
+	switch encoding {
+	case "base64", "base64url", "base32", "hex", "raw":
+		return true
+	default:
+		return false
+	}
 }
 
 func (pg StringGenerator) generateRandomSecret(conf secretConfig) error {
 	key := conf.key
 	instance := conf.instance
-	length := conf.length
-	isByteLength := conf.isByteLength
 
-	encoding, err := getEncodingFromAnnotation(DefaultEncoding(), instance.Annotations)
-	if err != nil {
-		return err
-	}
-	value, err := GenerateRandomString(length, encoding, isByteLength)
+	value, err := GenerateRandomString(conf.length, conf.encoding, conf.isByteLength)
 	if err != nil {
 		return err
 	}
 	const templateKey = "${SECRET}"
-	template, err := getTemplateFromAnnotation(templateKey, instance.Annotations)
-	if err != nil {
-		return err
-	}
-
-	value = bytes.ReplaceAll([]byte(template), []byte(templateKey), value)
+	value = bytes.ReplaceAll([]byte(conf.template), []byte(templateKey), value)
 
 	instance.Data[key] = value
 
-	pg.log.Info("set field of instance to new randomly generated instance", "bytes", len(value), "field", key, "encoding", encoding)
+	pg.log.Info("set field of instance to new randomly generated instance", "bytes", len(value), "field", key, "encoding", conf.encoding)
 
 	return nil
 }
@@ -118,7 +170,44 @@ func contains(s []string, e string) bool {
 	return false
 }
 
-func (pg StringGenerator) regenerateKeysWhereRequired(instance *corev1.Secret, genKeys []string) (reconcile.Result, error) {
+// resolveKeyConfig determines the length, encoding and template to use for key,
+// preferring its entry in keyConfigs and falling back to the secret-wide
+// annotations and cluster defaults when it has none, or when a field is left empty.
+func resolveKeyConfig(key string, keyConfigs map[string]keyConfig, annotations map[string]string) (length int, isByteLength bool, encoding string, template string, err error) {
+	conf := keyConfigs[key]
+
+	lengthRaw := conf.Length
+	if lengthRaw == "" {
+		lengthRaw, err = GetLengthFromAnnotation(DefaultLength(), annotations)
+		if err != nil {
+			return
+		}
+	}
+	length, isByteLength, err = ParseByteLength(DefaultLength(), lengthRaw)
+	if err != nil {
+		return
+	}
+
+	encoding = conf.Encoding
+	if encoding == "" {
+		encoding, err = getEncodingFromAnnotation(DefaultEncoding(), annotations)
+		if err != nil {
+			return
+		}
+	}
+
+	template = conf.Template
+	if template == "" {
+		template, err = getTemplateFromAnnotation("${SECRET}", annotations)
+		if err != nil {
+			return
+		}
+	}
+
+	return length, isByteLength, encoding, template, nil
+}
+
+func (pg StringGenerator) regenerateKeysWhereRequired(instance *corev1.Secret, genKeys []string, keyConfigs map[string]keyConfig) (reconcile.Result, error) {
 	var regenKeys []string
 
 	if _, ok := instance.Annotations[AnnotationSecretSecure]; !ok && RegenerateInsecure() {
@@ -135,16 +224,6 @@ func (pg StringGenerator) regenerateKeysWhereRequired(instance *corev1.Secret, g
 		}
 	}
 
-	length, err := GetLengthFromAnnotation(DefaultLength(), instance.Annotations)
-	if err != nil {
-		return reconcile.Result{}, err
-	}
-
-	parsedLength, isByteLength, err := ParseByteLength(DefaultLength(), length)
-	if err != nil {
-		return reconcile.Result{}, err
-	}
-
 	generatedCount := 0
 	for _, key := range genKeys {
 		if len(instance.Data[key]) != 0 && !contains(regenKeys, key) {
@@ -154,7 +233,12 @@ func (pg StringGenerator) regenerateKeysWhereRequired(instance *corev1.Secret, g
 		}
 		generatedCount++
 
-		err = pg.generateRandomSecret(secretConfig{instance, key, parsedLength, isByteLength})
+		length, isByteLength, encoding, template, err := resolveKeyConfig(key, keyConfigs, instance.Annotations)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		err = pg.generateRandomSecret(secretConfig{instance, key, length, isByteLength, encoding, template})
 		if err != nil {
 			pg.log.Error(err, "could not generate new random string")
 			return reconcile.Result{RequeueAfter: time.Second * 30}, err
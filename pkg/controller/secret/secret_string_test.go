@@ -0,0 +1,142 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseKeyConfigs(t *testing.T) {
+	t.Run("absent annotation yields no configs", func(t *testing.T) {
+		configs, err := parseKeyConfigs(map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs != nil {
+			t.Fatalf("expected nil configs, got %v", configs)
+		}
+	})
+
+	t.Run("valid json is parsed per key", func(t *testing.T) {
+		annotations := map[string]string{
+			AnnotationSecretKeys: `{"apikey":{"length":"32B","encoding":"hex"},"password":{"length":"24","template":"prefix-${SECRET}"}}`,
+		}
+
+		configs, err := parseKeyConfigs(annotations)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if configs["apikey"].Length != "32B" || configs["apikey"].Encoding != "hex" {
+			t.Fatalf("unexpected apikey config: %+v", configs["apikey"])
+		}
+		if configs["password"].Length != "24" || configs["password"].Template != "prefix-${SECRET}" {
+			t.Fatalf("unexpected password config: %+v", configs["password"])
+		}
+	})
+
+	t.Run("malformed json is rejected", func(t *testing.T) {
+		annotations := map[string]string{AnnotationSecretKeys: `{not-json`}
+
+		if _, err := parseKeyConfigs(annotations); err == nil {
+			t.Fatalf("expected an error for malformed json")
+		}
+	})
+
+	t.Run("unknown encoding is rejected", func(t *testing.T) {
+		annotations := map[string]string{
+			AnnotationSecretKeys: `{"apikey":{"encoding":"rot13"}}`,
+		}
+
+		if _, err := parseKeyConfigs(annotations); err == nil {
+			t.Fatalf("expected an error for unknown encoding")
+		}
+	})
+}
+
+func TestResolveKeyConfig(t *testing.T) {
+	viper.Set("secret-length", 16)
+	viper.Set("secret-encoding", "base64")
+	t.Cleanup(func() {
+		viper.Set("secret-length", 0)
+		viper.Set("secret-encoding", "")
+	})
+
+	t.Run("per-key config overrides secret-wide annotations", func(t *testing.T) {
+		keyConfigs := map[string]keyConfig{
+			"apikey": {Length: "32B", Encoding: "hex"},
+		}
+		annotations := map[string]string{
+			AnnotationSecretLength:   "8",
+			AnnotationSecretEncoding: "base32",
+		}
+
+		length, isByteLength, encoding, template, err := resolveKeyConfig("apikey", keyConfigs, annotations)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if length != 32 || !isByteLength {
+			t.Fatalf("expected 32-byte length, got %d (isByteLength=%v)", length, isByteLength)
+		}
+		if encoding != "hex" {
+			t.Fatalf("expected hex encoding, got %q", encoding)
+		}
+		if template != "${SECRET}" {
+			t.Fatalf("expected default template, got %q", template)
+		}
+	})
+
+	t.Run("key without an entry falls back to secret-wide annotations", func(t *testing.T) {
+		annotations := map[string]string{
+			AnnotationSecretLength:   "8",
+			AnnotationSecretEncoding: "base32",
+		}
+
+		length, isByteLength, encoding, _, err := resolveKeyConfig("password", nil, annotations)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if length != 8 || isByteLength {
+			t.Fatalf("expected length 8 (not byte length), got %d (isByteLength=%v)", length, isByteLength)
+		}
+		if encoding != "base32" {
+			t.Fatalf("expected base32 encoding, got %q", encoding)
+		}
+	})
+
+	t.Run("no annotations at all falls back to cluster defaults", func(t *testing.T) {
+		length, isByteLength, encoding, template, err := resolveKeyConfig("password", nil, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if length != 16 || isByteLength {
+			t.Fatalf("expected cluster default length 16, got %d (isByteLength=%v)", length, isByteLength)
+		}
+		if encoding != "base64" {
+			t.Fatalf("expected cluster default encoding base64, got %q", encoding)
+		}
+		if template != "${SECRET}" {
+			t.Fatalf("expected default template, got %q", template)
+		}
+	})
+
+	t.Run("partial per-key config fills remaining fields from fallbacks", func(t *testing.T) {
+		keyConfigs := map[string]keyConfig{
+			"apikey": {Encoding: "hex"},
+		}
+		annotations := map[string]string{
+			AnnotationSecretLength: "8",
+		}
+
+		length, isByteLength, encoding, _, err := resolveKeyConfig("apikey", keyConfigs, annotations)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if length != 8 || isByteLength {
+			t.Fatalf("expected length to fall back to secret-wide annotation 8, got %d", length)
+		}
+		if encoding != "hex" {
+			t.Fatalf("expected per-key encoding hex to win, got %q", encoding)
+		}
+	})
+}
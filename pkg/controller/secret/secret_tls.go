@@ -0,0 +1,182 @@
+package secret
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TypeTLS generates an X.509 keypair compatible with kubernetes.io/tls secrets.
+const TypeTLS Type = "tls"
+
+const (
+	// AnnotationSecretTLSCommonName sets the certificate's CommonName.
+	AnnotationSecretTLSCommonName = "secret-generator.v1.mittwald.de/cn"
+	// AnnotationSecretTLSSANs is a comma-separated list of subject alternative names,
+	// each either a DNS name or an IP address.
+	AnnotationSecretTLSSANs = "secret-generator.v1.mittwald.de/sans"
+	// AnnotationSecretTLSValidity is a duration string (e.g. "8760h") the generated
+	// certificate is valid for. Defaults to tlsDefaultValidity.
+	AnnotationSecretTLSValidity = "secret-generator.v1.mittwald.de/validity"
+	// AnnotationSecretTLSCASecret names a kubernetes.io/tls secret, in the same
+	// namespace, whose keypair should sign the generated certificate. If absent, a
+	// self-signed certificate is generated instead.
+	AnnotationSecretTLSCASecret = "secret-generator.v1.mittwald.de/ca-secret"
+
+	tlsDefaultValidity = 90 * 24 * time.Hour
+	tlsKeyBits         = 2048
+)
+
+// clusterClient is used by generators that need to read other objects from the
+// cluster (e.g. TLSGenerator fetching a CA secret) but, to keep the Generator
+// interface itself simple, are only constructed with a logger. It is set once by
+// NewReconciler.
+var clusterClient client.Client
+
+// SetClusterClient wires the client used by generators that need to read other
+// cluster objects, such as TLSGenerator resolving AnnotationSecretTLSCASecret.
+func SetClusterClient(c client.Client) {
+	clusterClient = c
+}
+
+// TLSGenerator creates a self-signed or CA-signed X.509 keypair under the
+// "tls.crt"/"tls.key" keys, compatible with kubernetes.io/tls secrets.
+type TLSGenerator struct {
+	log logr.Logger
+}
+
+func (g TLSGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
+	if len(instance.Data[corev1.TLSCertKey]) != 0 && len(instance.Data[corev1.TLSPrivateKeyKey]) != 0 {
+		if _, regen := instance.Annotations[AnnotationSecretRegenerate]; !regen {
+			return reconcile.Result{}, nil
+		}
+		delete(instance.Annotations, AnnotationSecretRegenerate)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, tlsKeyBits)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not generate tls private key: %w", err)
+	}
+
+	validity := tlsDefaultValidity
+	if raw, ok := instance.Annotations[AnnotationSecretTLSValidity]; ok {
+		validity, err = time.ParseDuration(raw)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretTLSValidity, err)
+		}
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not generate certificate serial number: %w", err)
+	}
+
+	// This is a leaf/serving certificate for a kubernetes.io/tls secret, never a CA:
+	// it must not carry KeyUsageCertSign or IsCA, or strict TLS stacks will reject it.
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: instance.Annotations[AnnotationSecretTLSCommonName]},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	applySANs(template, instance.Annotations[AnnotationSecretTLSSANs])
+
+	signerCert, signerKey := template, key
+	var caChainPEM []byte
+	if caSecretName, ok := instance.Annotations[AnnotationSecretTLSCASecret]; ok && caSecretName != "" {
+		var caCertPEM []byte
+		signerCert, signerKey, caCertPEM, err = g.loadCA(instance.Namespace, caSecretName)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		caChainPEM = caCertPEM
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not create certificate: %w", err)
+	}
+
+	// append the CA's certificate after the leaf so consumers can verify the chain
+	instance.Data[corev1.TLSCertKey] = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), caChainPEM...)
+	instance.Data[corev1.TLSPrivateKeyKey] = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	g.log.Info("generated tls keypair", "cn", template.Subject.CommonName, "validity", validity)
+
+	return reconcile.Result{}, nil
+}
+
+// loadCA fetches the CA certificate and private key from the named kubernetes.io/tls
+// secret so the generated certificate can be signed by it instead of self-signed. It
+// also returns the CA's own certificate PEM so callers can append it to the leaf
+// certificate and hand out the full chain.
+func (g TLSGenerator) loadCA(namespace, name string) (*x509.Certificate, *rsa.PrivateKey, []byte, error) {
+	if clusterClient == nil {
+		return nil, nil, nil, fmt.Errorf("no cluster client configured, cannot resolve ca-secret %q", name)
+	}
+
+	ca := &corev1.Secret{}
+	if err := clusterClient.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, ca); err != nil {
+		return nil, nil, nil, fmt.Errorf("could not load ca-secret %q: %w", name, err)
+	}
+
+	caCertPEM := ca.Data[corev1.TLSCertKey]
+	certBlock, _ := pem.Decode(caCertPEM)
+	if certBlock == nil {
+		return nil, nil, nil, fmt.Errorf("ca-secret %q has no %s", name, corev1.TLSCertKey)
+	}
+	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not parse ca certificate from %q: %w", name, err)
+	}
+
+	keyBlock, _ := pem.Decode(ca.Data[corev1.TLSPrivateKeyKey])
+	if keyBlock == nil {
+		return nil, nil, nil, fmt.Errorf("ca-secret %q has no %s", name, corev1.TLSPrivateKeyKey)
+	}
+	caKey, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not parse ca private key from %q: %w", name, err)
+	}
+
+	return caCert, caKey, caCertPEM, nil
+}
+
+// applySANs splits raw on commas and adds each entry to template as either an IP or
+// a DNS SAN.
+func applySANs(template *x509.Certificate, raw string) {
+	if raw == "" {
+		return
+	}
+
+	for _, san := range strings.Split(raw, ",") {
+		san = strings.TrimSpace(san)
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if san != "" {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+}
+
+func init() {
+	RegisterGenerator(TypeTLS, func(log logr.Logger) Generator {
+		return TLSGenerator{log: log}
+	})
+}